@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/time/rate"
+)
+
+// replayWindow 是设备签名请求允许的最大时间偏移，超过这个范围一律当作重放拒绝。
+const replayWindow = 5 * time.Minute
+
+// Device 是一台已配对手机在服务端的记录。
+type Device struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// initDeviceTable 和历史记录共用同一个 sqlite 文件。
+func initDeviceTable() error {
+	const schema = `
+CREATE TABLE IF NOT EXISTS devices (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	secret     TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	last_seen  DATETIME
+);`
+	_, err := historyDB.Exec(schema)
+	return err
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// createDevice 生成一个新的设备 id + 密钥并落库。
+func createDevice(name string) (Device, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return Device{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return Device{}, err
+	}
+
+	d := Device{ID: id, Name: name, Secret: secret, CreatedAt: time.Now()}
+	_, err = historyDB.Exec(
+		`INSERT INTO devices (id, name, secret, created_at) VALUES (?, ?, ?, ?)`,
+		d.ID, d.Name, d.Secret, d.CreatedAt,
+	)
+	return d, err
+}
+
+func listDevices() ([]Device, error) {
+	rows, err := historyDB.Query(`SELECT id, name, secret, created_at, last_seen FROM devices ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []Device
+	for rows.Next() {
+		var d Device
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Name, &d.Secret, &d.CreatedAt, &lastSeen); err != nil {
+			return nil, err
+		}
+		d.LastSeen = lastSeen.Time
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func getDevice(id string) (Device, error) {
+	var d Device
+	var lastSeen sql.NullTime
+	err := historyDB.QueryRow(
+		`SELECT id, name, secret, created_at, last_seen FROM devices WHERE id = ?`, id,
+	).Scan(&d.ID, &d.Name, &d.Secret, &d.CreatedAt, &lastSeen)
+	d.LastSeen = lastSeen.Time
+	return d, err
+}
+
+func deleteDevice(id string) error {
+	_, err := historyDB.Exec(`DELETE FROM devices WHERE id = ?`, id)
+	return err
+}
+
+func touchDevice(id string) {
+	if _, err := historyDB.Exec(`UPDATE devices SET last_seen = ? WHERE id = ?`, time.Now(), id); err != nil {
+		writeLog("更新设备 last_seen 失败:", err)
+	}
+}
+
+// ---------- 限流 ----------
+
+var (
+	deviceLimiters     = map[string]*rate.Limiter{}
+	deviceLimitersLock sync.Mutex
+)
+
+func limiterFor(deviceID string, perMinute int) *rate.Limiter {
+	if perMinute <= 0 {
+		perMinute = 30
+	}
+	deviceLimitersLock.Lock()
+	defer deviceLimitersLock.Unlock()
+	l, ok := deviceLimiters[deviceID]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(float64(perMinute)/60.0), perMinute)
+		deviceLimiters[deviceID] = l
+	}
+	return l
+}
+
+// ---------- 鉴权中间件 ----------
+
+// authMiddleware 接受两种身份：配置里固定的共享 Bearer token，或者按设备签名的
+// X-Device-Id + X-Timestamp + X-Signature。后者用设备密钥对 X-Timestamp 的原文
+// 紧接请求体做 HMAC-SHA256（十六进制），并拒绝超过 replayWindow 的时间戳防止重放。
+func authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := currentConfig()
+
+		if cfg.AuthToken != "" {
+			if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token == cfg.AuthToken {
+				c.Next()
+				return
+			}
+		}
+
+		deviceID := c.GetHeader("X-Device-Id")
+		timestamp := c.GetHeader("X-Timestamp")
+		signature := c.GetHeader("X-Signature")
+		if deviceID == "" || timestamp == "" || signature == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || absDuration(time.Since(time.Unix(ts, 0))) > replayWindow {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		device, err := getDevice(deviceID)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		mac := hmac.New(sha256.New, []byte(device.Secret))
+		mac.Write([]byte(timestamp))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !limiterFor(deviceID, cfg.RateLimitPerMinute).Allow() {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+
+		touchDevice(deviceID)
+		c.Next()
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// isLoopback 判断请求是否来自本机。依赖 startServer 里的
+// r.SetTrustedProxies(nil)：没有受信任的代理时 gin 只用 RemoteAddr 算
+// ClientIP，调用方没法用 X-Forwarded-For 伪造成 127.0.0.1。
+func isLoopback(c *gin.Context) bool {
+	ip := net.ParseIP(c.ClientIP())
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminAuthMiddleware 保护设备管理接口：要求配置了 admin_token 且请求携带
+// 匹配的 X-Admin-Token，并且只接受本机回环地址发起的请求。
+func adminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := currentConfig()
+		if cfg.AdminToken == "" || c.GetHeader("X-Admin-Token") != cfg.AdminToken {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if !isLoopback(c) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// guardReadOnly 保护只读 / 展示类接口（/config、/ws、历史查看）：本机直接放行，
+// 否则要求和 /copy、/msg 一样的共享 Bearer token，避免局域网内任何人都能围观
+// 历史验证码、当前配置或 WebSocket 推送。
+func guardReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isLoopback(c) {
+			c.Next()
+			return
+		}
+
+		cfg := currentConfig()
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if cfg.AuthToken != "" && token == cfg.AuthToken {
+			c.Next()
+			return
+		}
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
+
+// ---------- 路由 ----------
+
+func registerAuthRoutes(r *gin.Engine) {
+	admin := r.Group("/admin", adminAuthMiddleware())
+
+	admin.POST("/devices", func(c *gin.Context) {
+		name := c.PostForm("name")
+		d, err := createDevice(name)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, d)
+	})
+
+	admin.GET("/devices", func(c *gin.Context) {
+		devices, err := listDevices()
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, devices)
+	})
+
+	admin.DELETE("/devices/:id", func(c *gin.Context) {
+		if err := deleteDevice(c.Param("id")); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "success")
+	})
+
+	admin.GET("/devices/:id/qrcode", func(c *gin.Context) {
+		d, err := getDevice(c.Param("id"))
+		if err != nil {
+			c.String(http.StatusNotFound, "not found")
+			return
+		}
+		png, err := pairingQRCode(d)
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "image/png", png)
+	})
+}
+
+// pairingQRCode 生成一张编码了 {url, device_id, secret} 的二维码图片，
+// 供安卓端的发送 App 扫码完成配对。
+func pairingQRCode(d Device) ([]byte, error) {
+	payload, err := json.Marshal(map[string]string{
+		"url":       "http://" + pairingHost() + "/copy",
+		"device_id": d.ID,
+		"secret":    d.Secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return qrcode.Encode(string(payload), qrcode.Medium, 256)
+}
+
+// pairingHost 返回配对二维码里使用的 host:port。和 historyViewURL() 里的回环
+// 替换不同，这里生成的地址要被手机上的发送 App 访问，所以不能直接把 ":9002"
+// 这样的 listen 值换成 127.0.0.1——那指向的是手机自己。优先用配置里显式指定
+// 的 public_host，其次尝试探测一个局域网 IPv4 地址，都拿不到时才退回 listen
+// 地址本身，只有端口（如 ":9002"）时补成 127.0.0.1（至少配对流程不会直接崩，
+// 但多半要手动改配置才能真正用起来）。
+func pairingHost() string {
+	cfg := currentConfig()
+	port := listenPort(cfg.Listen)
+
+	if cfg.PublicHost != "" {
+		if port == "" {
+			return cfg.PublicHost
+		}
+		return net.JoinHostPort(cfg.PublicHost, port)
+	}
+
+	if ip := firstLANAddr(); ip != "" && port != "" {
+		return net.JoinHostPort(ip, port)
+	}
+
+	addr := cfg.Listen
+	if len(addr) > 0 && addr[0] == ':' {
+		return "127.0.0.1" + addr
+	}
+	return addr
+}
+
+// listenPort 从形如 ":9002" 或 "0.0.0.0:9002" 的 listen 地址里取出端口号。
+func listenPort(addr string) string {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// firstLANAddr 在本机网卡里找一个非回环、非链路本地的 IPv4 地址，用作手机
+// 扫码时应该访问的服务器地址。找不到就返回空字符串，调用方自行回退。
+func firstLANAddr() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		ip4 := ipNet.IP.To4()
+		if ip4 == nil || ip4.IsLinkLocalUnicast() {
+			continue
+		}
+		return ip4.String()
+	}
+	return ""
+}
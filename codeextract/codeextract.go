@@ -0,0 +1,96 @@
+// Package codeextract 从任意文本里挑出短信验证码。规则是一张按优先级排序的
+// 列表，每条规则是一个带命名捕获组 code 的正则；全部规则都不命中时，退化到一个
+// 启发式算法：在关键字附近找一个不像手机号、也不像年份的 4~8 位数字串，
+// 关键字附近没有这样的数字串时不返回验证码。
+package codeextract
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// RuleConfig 是配置文件里 code_extract_rules 列表的一项。
+type RuleConfig struct {
+	Name     string `yaml:"name" json:"name"`
+	Pattern  string `yaml:"pattern" json:"pattern"`
+	Language string `yaml:"language" json:"language"`
+	Priority int    `yaml:"priority" json:"priority"`
+}
+
+// Rule 是编译后的规则，Regex 必须包含一个名为 code 的捕获组。
+type Rule struct {
+	Name     string
+	Regex    *regexp.Regexp
+	Language string
+	Priority int
+}
+
+// Result 是一次提取的结果。
+type Result struct {
+	Code       string  `json:"code"`
+	Rule       string  `json:"rule"`
+	Confidence float64 `json:"confidence"`
+}
+
+const fallbackRuleName = "fallback-heuristic"
+
+// BuiltinRuleConfigs 是配置文件缺少 code_extract_rules 时使用的内置规则，
+// 覆盖常见的中英文验证码措辞。
+func BuiltinRuleConfigs() []RuleConfig {
+	return []RuleConfig{
+		{Name: "验证码", Pattern: `验证码[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "zh", Priority: 100},
+		{Name: "校验码", Pattern: `校验码[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "zh", Priority: 100},
+		{Name: "动态码", Pattern: `动态码[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "zh", Priority: 100},
+		{Name: "verification-code", Pattern: `(?i)verification code[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "en", Priority: 90},
+		{Name: "code-is", Pattern: `(?i)code is[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "en", Priority: 80},
+		{Name: "otp", Pattern: `(?i)OTP[\s\S]{0,10}?(?P<code>\d{4,8})`, Language: "en", Priority: 80},
+	}
+}
+
+// Compile 把规则配置编译成可用的 Rule 列表，按优先级从高到低排序。
+func Compile(cfgs []RuleConfig) ([]Rule, error) {
+	rules := make([]Rule, 0, len(cfgs))
+	for _, c := range cfgs {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("规则 %q 正则编译失败: %w", c.Name, err)
+		}
+		if re.SubexpIndex("code") < 0 {
+			return nil, fmt.Errorf("规则 %q 缺少命名捕获组 code", c.Name)
+		}
+		rules = append(rules, Rule{Name: c.Name, Regex: re, Language: c.Language, Priority: c.Priority})
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority > rules[j].Priority })
+	return rules, nil
+}
+
+// Extractor 按优先级依次尝试规则，全部失败时使用启发式兜底。
+type Extractor struct {
+	rules []Rule
+}
+
+// NewExtractor 用已经编译好的规则构造 Extractor。
+func NewExtractor(rules []Rule) *Extractor {
+	return &Extractor{rules: rules}
+}
+
+// Extract 返回选中的验证码、命中的规则名以及一个粗略的置信度。
+func (e *Extractor) Extract(text string) Result {
+	for _, r := range e.rules {
+		m := r.Regex.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		idx := r.Regex.SubexpIndex("code")
+		if idx < 0 || idx >= len(m) || m[idx] == "" {
+			continue
+		}
+		return Result{Code: m[idx], Rule: r.Name, Confidence: 0.95}
+	}
+
+	if code, ok := fallbackExtract(text); ok {
+		return Result{Code: code, Rule: fallbackRuleName, Confidence: 0.5}
+	}
+	return Result{}
+}
@@ -0,0 +1,105 @@
+package codeextract
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// fallbackKeywords 是启发式兜底时用来判断"这串数字像不像验证码"的关键字，
+// 覆盖内置规则之外可能出现的措辞。
+var fallbackKeywords = []string{
+	"验证码", "校验码", "动态码", "code", "verification", "otp", "密码",
+}
+
+// fallbackProximity 是数字串与关键字之间允许的最大距离（字符数）。
+const fallbackProximity = 20
+
+var digitRunRe = regexp.MustCompile(`\d+`)
+
+// fallbackExtract 在找不到任何已知规则命中时使用：在长度 4~8、不是手机号
+// （11 位连续数字天然被排除）、不是年份（1900~2099）的数字串里，只考虑离某个
+// 关键字不超过 fallbackProximity 个字符的，从中选最长的一个；一个都没有时返回 false。
+func fallbackExtract(text string) (string, bool) {
+	type candidate struct {
+		value string
+		start int
+	}
+	var candidates []candidate
+	for _, loc := range digitRunRe.FindAllStringIndex(text, -1) {
+		run := text[loc[0]:loc[1]]
+		if len(run) < 4 || len(run) > 8 {
+			continue
+		}
+		if isYear(run) {
+			continue
+		}
+		candidates = append(candidates, candidate{value: run, start: charIndex(text, loc[0])})
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	keywordPositions := findKeywordPositions(text)
+
+	best := candidates[0]
+	bestDist := nearestDistance(best.start, keywordPositions)
+	for _, c := range candidates[1:] {
+		d := nearestDistance(c.start, keywordPositions)
+		switch {
+		case d <= fallbackProximity && bestDist > fallbackProximity:
+			best, bestDist = c, d
+		case d <= fallbackProximity && bestDist <= fallbackProximity:
+			if len(c.value) > len(best.value) {
+				best, bestDist = c, d
+			}
+		}
+	}
+	if bestDist > fallbackProximity {
+		return "", false
+	}
+	return best.value, true
+}
+
+func isYear(run string) bool {
+	if len(run) != 4 {
+		return false
+	}
+	n, err := strconv.Atoi(run)
+	if err != nil {
+		return false
+	}
+	return n >= 1900 && n <= 2099
+}
+
+// charIndex 把一个字节偏移转换成 rune 下标，供关键字邻近度计算使用。
+func charIndex(text string, byteOffset int) int {
+	return len([]rune(text[:byteOffset]))
+}
+
+func findKeywordPositions(text string) []int {
+	var positions []int
+	for _, kw := range fallbackKeywords {
+		re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(kw))
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			positions = append(positions, charIndex(text, loc[0]))
+		}
+	}
+	return positions
+}
+
+func nearestDistance(pos int, keywordPositions []int) int {
+	best := -1
+	for _, kp := range keywordPositions {
+		d := pos - kp
+		if d < 0 {
+			d = -d
+		}
+		if best == -1 || d < best {
+			best = d
+		}
+	}
+	if best == -1 {
+		return 1 << 30
+	}
+	return best
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"sms_tray/codeextract"
+	"sms_tray/forwarder"
+)
+
+// configFileName 是配置文件名，与可执行文件放在同一目录。
+const configFileName = "sms-service.yaml"
+
+// AppConfig 是可热重载的运行期配置。
+type AppConfig struct {
+	Listen string `yaml:"listen" json:"listen"`
+	// PublicHost 是手机在局域网里访问本机时应该使用的地址（不含端口）。留空
+	// 时配对二维码会尝试自动探测一个局域网 IP，而不是直接回环替换 Listen，
+	// 因为 "127.0.0.1" 对扫码的手机来说指向的是它自己。
+	PublicHost string                 `yaml:"public_host" json:"public_host"`
+	AutoPaste  bool                   `yaml:"auto_paste" json:"auto_paste"`
+	ToastAppID string                 `yaml:"toast_app_id" json:"toast_app_id"`
+	Forwarders []forwarder.SinkConfig `yaml:"forwarders" json:"forwarders"`
+
+	CodeExtractRules []codeextract.RuleConfig `yaml:"code_extract_rules" json:"code_extract_rules"`
+
+	HistoryRetentionDays int `yaml:"history_retention_days" json:"history_retention_days"`
+
+	// AuthToken 是 /copy、/msg 接受的共享 Bearer token；为空时只接受按设备签名的请求。
+	// 不通过 /config 暴露，避免把密钥原样回显给任何能访问该接口的人。
+	AuthToken string `yaml:"auth_token" json:"-"`
+	// AdminToken 保护 /admin/devices 系列接口，只接受本机回环地址发起的请求。
+	AdminToken         string `yaml:"admin_token" json:"-"`
+	RateLimitPerMinute int    `yaml:"rate_limit_per_minute" json:"rate_limit_per_minute"`
+}
+
+// defaultConfig 返回配置文件缺失时使用的默认值。
+func defaultConfig() AppConfig {
+	return AppConfig{
+		Listen:               ":9002",
+		AutoPaste:            true,
+		ToastAppID:           "短信服务",
+		CodeExtractRules:     codeextract.BuiltinRuleConfigs(),
+		HistoryRetentionDays: 30,
+		RateLimitPerMinute:   30,
+	}
+}
+
+var (
+	appConfig     AppConfig
+	appConfigLock sync.RWMutex
+)
+
+// loadConfig 从 sms-service.yaml 读取配置，文件不存在或解析失败时回退到默认值。
+func loadConfig() AppConfig {
+	cfg := defaultConfig()
+
+	path := configFileName
+	if exe, err := os.Executable(); err == nil {
+		path = filepath.Join(filepath.Dir(exe), configFileName)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			writeLog("读取配置文件失败:", err)
+		}
+		return cfg
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		writeLog("解析配置文件失败:", err)
+		return defaultConfig()
+	}
+
+	return cfg
+}
+
+// reloadConfig 重新加载配置文件并原子替换当前生效配置。
+func reloadConfig() AppConfig {
+	cfg := loadConfig()
+	appConfigLock.Lock()
+	appConfig = cfg
+	appConfigLock.Unlock()
+	writeLog("配置已重新加载:", summarizeConfig(cfg))
+	return cfg
+}
+
+// summarizeConfig 返回一份不含密钥的配置摘要，专门用于日志输出——AuthToken、
+// AdminToken 以及每个 forwarder 的 params（webhook 签名密钥、SMTP 密码）都不
+// 应该原样写进 sms-service.log。
+func summarizeConfig(cfg AppConfig) string {
+	return fmt.Sprintf(
+		"listen=%s public_host=%s auto_paste=%v history_retention_days=%d rate_limit_per_minute=%d "+
+			"auth_token_set=%v admin_token_set=%v forwarders=%d code_extract_rules=%d",
+		cfg.Listen, cfg.PublicHost, cfg.AutoPaste, cfg.HistoryRetentionDays, cfg.RateLimitPerMinute,
+		cfg.AuthToken != "", cfg.AdminToken != "", len(cfg.Forwarders), len(cfg.CodeExtractRules),
+	)
+}
+
+// currentConfig 返回当前生效的配置副本。
+func currentConfig() AppConfig {
+	appConfigLock.RLock()
+	defer appConfigLock.RUnlock()
+	return appConfig
+}
@@ -0,0 +1,107 @@
+// Package forwarder 把收到的短信扇出到一组可配置的下游：出站 webhook、
+// WebSocket 推送、关键字匹配的邮件通知等。每个 sink 独立重试，互不阻塞，
+// 失败只记录日志，不影响 /copy 和 /msg 对手机端的响应。
+package forwarder
+
+import (
+	"fmt"
+	"time"
+)
+
+// Message 是投递给各个 sink 的统一消息体。
+type Message struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Raw    string    `json:"raw"`
+	Code   string    `json:"code"`
+}
+
+// SinkConfig 描述配置文件里 forwarders 列表的一项。Params 里通常带着 webhook
+// 的签名密钥、SMTP 的密码这类敏感信息，所以不参与 JSON 序列化——GET /config
+// 只应该回显 sink 的类型和名字，不能把密钥原样吐给任何能访问该接口的人。
+type SinkConfig struct {
+	Type   string            `yaml:"type" json:"type"`
+	Name   string            `yaml:"name" json:"name"`
+	Params map[string]string `yaml:"params" json:"-"`
+}
+
+// Sink 是一个转发目标。
+type Sink interface {
+	Name() string
+	Send(msg Message) error
+}
+
+// Logger 让调用方复用自己现有的日志管道，forwarder 包本身不关心写到哪里。
+type Logger func(v ...any)
+
+const (
+	maxRetries   = 3
+	retryBackoff = 500 * time.Millisecond
+)
+
+// Hub 管理一组 sink，每次 Dispatch 都会把消息并发扇出给所有 sink。
+type Hub struct {
+	sinks  []Sink
+	logger Logger
+}
+
+// NewHub 用已经建好的 sink 列表构造 Hub。
+func NewHub(sinks []Sink, logger Logger) *Hub {
+	return &Hub{sinks: sinks, logger: logger}
+}
+
+// BuildSinks 把配置文件里的 forwarders 列表转换成 Sink 实例。
+// websocket 类型比较特殊：它复用调用方已经挂载到 /ws 路由上的 hub，
+// 而不是每次都新建一个。
+func BuildSinks(cfgs []SinkConfig, wsHub *WSHub, logger Logger) []Sink {
+	var sinks []Sink
+	for _, c := range cfgs {
+		switch c.Type {
+		case "webhook":
+			s, err := newWebhookSink(c)
+			if err != nil {
+				logger("创建 webhook 转发目标失败:", c.Name, err)
+				continue
+			}
+			sinks = append(sinks, s)
+		case "smtp":
+			s, err := newSMTPSink(c)
+			if err != nil {
+				logger("创建 smtp 转发目标失败:", c.Name, err)
+				continue
+			}
+			sinks = append(sinks, s)
+		case "websocket":
+			if wsHub == nil {
+				logger("websocket 转发目标缺少 hub，已跳过:", c.Name)
+				continue
+			}
+			sinks = append(sinks, wsHub)
+		default:
+			logger("未知的转发目标类型:", c.Type)
+		}
+	}
+	return sinks
+}
+
+// Dispatch 把一条消息并发投递给所有 sink，每个 sink 在自己的 goroutine 里跑，
+// 失败时按 retryBackoff 指数退避重试，最终仍失败只记日志，不向上抛错。
+func (h *Hub) Dispatch(msg Message) {
+	for _, s := range h.sinks {
+		go h.sendWithRetry(s, msg)
+	}
+}
+
+func (h *Hub) sendWithRetry(s Sink, msg Message) {
+	backoff := retryBackoff
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if err := s.Send(msg); err != nil {
+			h.logger(fmt.Sprintf("转发到 %s 失败(第 %d/%d 次): %v", s.Name(), attempt, maxRetries, err))
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		return
+	}
+	h.logger(fmt.Sprintf("转发到 %s 最终失败，已放弃", s.Name()))
+}
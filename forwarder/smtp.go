@@ -0,0 +1,88 @@
+package forwarder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// smtpSink 在短信命中配置的关键字时才发一封邮件通知，避免把每条短信都转发到邮箱。
+type smtpSink struct {
+	name     string
+	dialer   *gomail.Dialer
+	from     string
+	to       []string
+	keywords []string
+}
+
+func newSMTPSink(c SinkConfig) (*smtpSink, error) {
+	host := c.Params["host"]
+	from := c.Params["from"]
+	to := c.Params["to"]
+	if host == "" || from == "" || to == "" {
+		return nil, fmt.Errorf("smtp 缺少 host/from/to 参数")
+	}
+
+	port := 587
+	if p := c.Params["port"]; p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("smtp port 无法解析: %w", err)
+		}
+		port = parsed
+	}
+
+	dialer := gomail.NewDialer(host, port, c.Params["username"], c.Params["password"])
+
+	var keywords []string
+	if kw := c.Params["keywords"]; kw != "" {
+		for _, k := range strings.Split(kw, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keywords = append(keywords, k)
+			}
+		}
+	}
+	if len(keywords) == 0 {
+		return nil, fmt.Errorf("smtp 缺少 keywords 参数：不配置关键字会把每条短信都转发到邮箱")
+	}
+
+	return &smtpSink{
+		name:     c.Name,
+		dialer:   dialer,
+		from:     from,
+		to:       strings.Split(to, ","),
+		keywords: keywords,
+	}, nil
+}
+
+func (s *smtpSink) Name() string {
+	if s.name != "" {
+		return s.name
+	}
+	return "smtp:" + s.from
+}
+
+func (s *smtpSink) Send(msg Message) error {
+	if !s.matches(msg.Raw) {
+		return nil
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.from)
+	m.SetHeader("To", s.to...)
+	m.SetHeader("Subject", fmt.Sprintf("[短信转发] %s", msg.Source))
+	m.SetBody("text/plain", msg.Raw)
+
+	return s.dialer.DialAndSend(m)
+}
+
+func (s *smtpSink) matches(raw string) bool {
+	for _, kw := range s.keywords {
+		if strings.Contains(raw, kw) {
+			return true
+		}
+	}
+	return false
+}
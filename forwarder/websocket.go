@@ -0,0 +1,76 @@
+package forwarder
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// WSHub 维护所有通过 /ws 连接上来的浏览器/桌面客户端，
+// 每次 Send 都把消息广播给当前所有连接。它同时实现了 Sink 接口，
+// 所以可以和 webhook、smtp 一样出现在 forwarders 配置列表里。
+type WSHub struct {
+	upgrader websocket.Upgrader
+	mu       sync.Mutex
+	clients  map[*websocket.Conn]bool
+}
+
+// NewWSHub 创建一个空的 WebSocket 推送 hub。
+func NewWSHub() *WSHub {
+	return &WSHub{
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients: make(map[*websocket.Conn]bool),
+	}
+}
+
+func (h *WSHub) Name() string {
+	return "websocket"
+}
+
+// ServeHTTP 把一个 HTTP 连接升级成 WebSocket 并注册为订阅者，
+// 直到对端断开为止。
+func (h *WSHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.clients[conn] = true
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, conn)
+		h.mu.Unlock()
+		conn.Close()
+	}()
+
+	// 只负责推送，不处理客户端上行消息；持续读取只是为了检测连接关闭。
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *WSHub) Send(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn := range h.clients {
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			conn.Close()
+			delete(h.clients, conn)
+		}
+	}
+	return nil
+}
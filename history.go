@@ -0,0 +1,254 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "modernc.org/sqlite"
+)
+
+// historyDBPath 与可执行文件放在同一目录，避免依赖外部数据库服务。
+const historyDBPath = "sms-history.db"
+
+var historyDB *sql.DB
+
+// HistoryEntry 对应 sms-history.db 里的一行记录。
+type HistoryEntry struct {
+	ID            int64     `json:"id"`
+	ReceivedAt    time.Time `json:"received_at"`
+	Endpoint      string    `json:"endpoint"`
+	RawContent    string    `json:"raw_content"`
+	ExtractedCode string    `json:"extracted_code"`
+	SourceIP      string    `json:"source_ip"`
+}
+
+// initHistoryDB 打开（必要时创建）历史记录数据库。
+func initHistoryDB() error {
+	path := historyDBPath
+	if exe, err := os.Executable(); err == nil {
+		path = filepath.Join(filepath.Dir(exe), historyDBPath)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	received_at    DATETIME NOT NULL,
+	endpoint       TEXT NOT NULL,
+	raw_content    TEXT NOT NULL,
+	extracted_code TEXT NOT NULL DEFAULT '',
+	source_ip      TEXT NOT NULL DEFAULT ''
+);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return err
+	}
+
+	historyDB = db
+	return nil
+}
+
+// recordHistory 插入一条收到的短信记录，失败只记日志，不影响主流程。
+func recordHistory(endpoint, raw, code, sourceIP string) {
+	if historyDB == nil {
+		return
+	}
+	_, err := historyDB.Exec(
+		`INSERT INTO history (received_at, endpoint, raw_content, extracted_code, source_ip) VALUES (?, ?, ?, ?, ?)`,
+		time.Now(), endpoint, raw, code, sourceIP,
+	)
+	if err != nil {
+		writeLog("写入历史记录失败:", err)
+	}
+}
+
+// queryHistory 支持按数量、起始时间和关键字过滤。
+func queryHistory(limit int, since time.Time, q string) ([]HistoryEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := historyDB.Query(
+		`SELECT id, received_at, endpoint, raw_content, extracted_code, source_ip
+		 FROM history
+		 WHERE received_at >= ? AND raw_content LIKE ?
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		since, "%"+q+"%", limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		if err := rows.Scan(&e.ID, &e.ReceivedAt, &e.Endpoint, &e.RawContent, &e.ExtractedCode, &e.SourceIP); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// getHistoryEntry 取单条记录，用于历史查看页的"复制验证码"按钮。
+func getHistoryEntry(id int64) (HistoryEntry, error) {
+	var e HistoryEntry
+	err := historyDB.QueryRow(
+		`SELECT id, received_at, endpoint, raw_content, extracted_code, source_ip FROM history WHERE id = ?`, id,
+	).Scan(&e.ID, &e.ReceivedAt, &e.Endpoint, &e.RawContent, &e.ExtractedCode, &e.SourceIP)
+	return e, err
+}
+
+// writeHistoryCSV 把查询结果以 CSV 形式写出，供 /history.csv 导出使用。
+func writeHistoryCSV(w io.Writer, entries []HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "received_at", "endpoint", "raw_content", "extracted_code", "source_ip"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			strconv.FormatInt(e.ID, 10),
+			e.ReceivedAt.Format(time.RFC3339),
+			e.Endpoint,
+			e.RawContent,
+			e.ExtractedCode,
+			e.SourceIP,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// startHistoryJanitor 每天清理一次超出保留期的历史记录。
+func startHistoryJanitor(retentionDays int) {
+	if retentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanupHistory(retentionDays)
+		}
+	}()
+}
+
+func cleanupHistory(retentionDays int) {
+	if historyDB == nil {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	res, err := historyDB.Exec(`DELETE FROM history WHERE received_at < ?`, cutoff)
+	if err != nil {
+		writeLog("清理历史记录失败:", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		writeLog("历史记录保留策略清理了", n, "条过期记录")
+	}
+}
+
+// historyViewPage 是内嵌的历史记录查看页，由同一个 Gin 实例提供。
+const historyViewPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><title>短信历史</title></head>
+<body>
+<h3>最近短信</h3>
+<table border="1" cellpadding="6" id="tbl">
+<tr><th>时间</th><th>来源</th><th>内容</th><th>验证码</th><th></th></tr>
+</table>
+<script>
+function cell(text) {
+	const td = document.createElement('td');
+	td.textContent = text;
+	return td;
+}
+
+fetch('/history?limit=200').then(r => r.json()).then(list => {
+	const tbl = document.getElementById('tbl');
+	(list || []).forEach(item => {
+		const tr = document.createElement('tr');
+		tr.appendChild(cell(item.received_at));
+		tr.appendChild(cell(item.endpoint));
+		tr.appendChild(cell(item.raw_content));
+		tr.appendChild(cell(item.extracted_code));
+
+		const actions = document.createElement('td');
+		const btn = document.createElement('button');
+		btn.textContent = '复制验证码';
+		btn.onclick = () => fetch('/history/' + item.id + '/copy', {method: 'POST'});
+		actions.appendChild(btn);
+		tr.appendChild(actions);
+
+		tbl.appendChild(tr);
+	});
+});
+</script>
+</body>
+</html>`
+
+func registerHistoryRoutes(r *gin.Engine) {
+	r.GET("/history", guardReadOnly(), func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		var since time.Time
+		if s := c.Query("since"); s != "" {
+			if parsed, err := time.Parse(time.RFC3339, s); err == nil {
+				since = parsed
+			}
+		}
+		entries, err := queryHistory(limit, since, c.Query("q"))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	})
+
+	r.GET("/history.csv", guardReadOnly(), func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		entries, err := queryHistory(limit, time.Time{}, c.Query("q"))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="history.csv"`)
+		if err := writeHistoryCSV(c.Writer, entries); err != nil {
+			writeLog("导出历史 CSV 失败:", err)
+		}
+	})
+
+	r.GET("/history/view", guardReadOnly(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(historyViewPage))
+	})
+
+	r.POST("/history/:id/copy", guardReadOnly(), func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "invalid id")
+			return
+		}
+		entry, err := getHistoryEntry(id)
+		if err != nil {
+			c.String(http.StatusNotFound, "not found")
+			return
+		}
+		copyCodeToClipboard(entry.ExtractedCode)
+		c.String(http.StatusOK, "success")
+	})
+}
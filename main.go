@@ -1,23 +1,26 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"sync"
 	"time"
 
 	"github.com/getlantern/systray"
 	"github.com/gin-gonic/gin"
-	"github.com/go-toast/toast"
+	"github.com/kardianos/service"
 	"golang.design/x/clipboard"
-	"golang.org/x/sys/windows"
-	"golang.org/x/sys/windows/registry"
+
+	"sms_tray/codeextract"
+	"sms_tray/forwarder"
+	"sms_tray/platform"
 )
 
 //go:embed assets/icon.ico
@@ -29,36 +32,254 @@ var (
 	serverThread  *httpServerWrapper
 	logFile       *os.File
 	logLock       sync.Mutex
+
+	svcFlag = flag.String("service", "", "控制系统服务: install | uninstall | start | stop | run")
+
+	wsHub  = forwarder.NewWSHub()
+	fwHub  *forwarder.Hub
+	fwLock sync.RWMutex
+
+	extractor     *codeextract.Extractor
+	extractorLock sync.RWMutex
+
+	notifier = platform.NewNotifier()
+	autoRun  = platform.NewAutoRun()
 )
 
-// ---------- 包装 gin.Server 用于控制启动停止 ----------
+// applyConfig 根据最新配置重建转发 sink 列表和验证码提取规则。
+// websocket hub 本身是长期存活的，只有 webhook/smtp 这类无状态 sink 会随配置
+// 变化整体替换。
+func applyConfig(cfg AppConfig) {
+	sinks := forwarder.BuildSinks(cfg.Forwarders, wsHub, writeLog)
+	hub := forwarder.NewHub(sinks, writeLog)
+	fwLock.Lock()
+	fwHub = hub
+	fwLock.Unlock()
+
+	rules, err := codeextract.Compile(cfg.CodeExtractRules)
+	if err != nil {
+		writeLog("编译验证码提取规则失败，沿用内置规则:", err)
+		rules, _ = codeextract.Compile(codeextract.BuiltinRuleConfigs())
+	}
+	extractorLock.Lock()
+	extractor = codeextract.NewExtractor(rules)
+	extractorLock.Unlock()
+}
+
+// currentExtractor 返回当前生效的验证码提取器。
+func currentExtractor() *codeextract.Extractor {
+	extractorLock.RLock()
+	defer extractorLock.RUnlock()
+	return extractor
+}
+
+// historyViewURL 把配置里的 listen 地址（可能只是 ":9002"）拼成可以在浏览器里
+// 打开的历史查看页地址。
+func historyViewURL() string {
+	addr := currentConfig().Listen
+	if len(addr) > 0 && addr[0] == ':' {
+		addr = "127.0.0.1" + addr
+	}
+	return "http://" + addr + "/history/view"
+}
+
+// pairNewDevice 生成一台新设备并把配对二维码写到临时文件，用系统默认的图片
+// 查看器打开，方便直接用手机扫码完成配对。
+func pairNewDevice() {
+	d, err := createDevice("")
+	if err != nil {
+		writeLog("创建配对设备失败:", err)
+		return
+	}
+
+	png, err := pairingQRCode(d)
+	if err != nil {
+		writeLog("生成配对二维码失败:", err)
+		return
+	}
+
+	qrPath := filepath.Join(os.TempDir(), "sms_pair_"+d.ID+".png")
+	if err := os.WriteFile(qrPath, png, 0644); err != nil {
+		writeLog("写入配对二维码失败:", err)
+		return
+	}
+
+	if err := platform.Open(qrPath); err != nil {
+		writeLog("打开配对二维码失败:", err)
+	}
+	writeLog("已生成配对二维码，设备:", d.ID)
+}
+
+func dispatchForward(msg forwarder.Message) {
+	fwLock.RLock()
+	hub := fwHub
+	fwLock.RUnlock()
+	if hub != nil {
+		hub.Dispatch(msg)
+	}
+}
+
+// ---------- 系统服务 ----------
+
+// svcConfig 描述注册到 SCM 的服务信息。Option 里的 OnFailure 让 Windows 在
+// 进程异常退出时由 SCM 自动拉起，而不是停在那里等人手动重启。
+var svcConfig = &service.Config{
+	Name:        "sms-tray-service",
+	DisplayName: "短信验证码托盘服务",
+	Description: "接收手机短信并自动提取验证码、粘贴到剪贴板",
+	Option: service.KeyValue{
+		"OnFailure":              "restart",
+		"OnFailureDelayDuration": "5s",
+		"OnFailureResetPeriod":   600,
+	},
+}
+
+// program 是 kardianos/service 的 service.Interface 实现，
+// 把现有的 Gin HTTP 服务包装成可被 SCM 启动/停止的服务。
+type program struct {
+	svc service.Service
+}
+
+func (p *program) Start(s service.Service) error {
+	// Start 不能阻塞，HTTP 服务在独立 goroutine 中运行。
+	startServer()
+	serverRunning = true
+	writeLog("服务已由 SCM 启动")
+	return nil
+}
+
+func (p *program) Stop(s service.Service) error {
+	stopServer()
+	serverRunning = false
+	writeLog("服务已由 SCM 停止")
+	return nil
+}
+
+// installService 安装并启动系统服务。
+func installService() error {
+	s, err := service.New(&program{}, svcConfig)
+	if err != nil {
+		return err
+	}
+	if err := s.Install(); err != nil {
+		return err
+	}
+	return s.Start()
+}
+
+// uninstallService 停止并卸载系统服务。
+func uninstallService() error {
+	s, err := service.New(&program{}, svcConfig)
+	if err != nil {
+		return err
+	}
+	s.Stop()
+	return s.Uninstall()
+}
+
+// isServiceInstalled 判断服务是否已经安装到 SCM。
+func isServiceInstalled() bool {
+	s, err := service.New(&program{}, svcConfig)
+	if err != nil {
+		return false
+	}
+	_, err = s.Status()
+	return err == nil
+}
+
+// ---------- 包装 http.Server 用于控制启动停止 ----------
 type httpServerWrapper struct {
-	addr   string
-	server *gin.Engine
-	stopCh chan struct{}
+	addr string
+	srv  *http.Server
 }
 
 func (s *httpServerWrapper) Start() {
 	go func() {
 		writeLog("监听端口", s.addr)
-		err := s.server.Run(s.addr)
-		if err != nil {
+		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			writeLog("HTTP 启动错误:", err)
 		}
 	}()
 }
 
 func (s *httpServerWrapper) Stop() {
-	// Gin 没有直接 Close，需要 http.Server 实例时才能优雅关闭。
-	// 这里可以简单退出 goroutine。
-	close(s.stopCh)
-	writeLog("HTTP 服务关闭")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		writeLog("HTTP 优雅关闭失败:", err)
+		return
+	}
+	writeLog("HTTP 服务已优雅关闭")
 }
 
 // go build -ldflags="-H=windowsgui" -o sms_service.exe
 // ---------- 主函数 ----------
 func main() {
+	flag.Parse()
 	setupLog()
+	cfg := reloadConfig()
+	applyConfig(cfg)
+
+	if err := initHistoryDB(); err != nil {
+		writeLog("打开历史数据库失败:", err)
+	} else {
+		startHistoryJanitor(cfg.HistoryRetentionDays)
+		if err := initDeviceTable(); err != nil {
+			writeLog("初始化设备表失败:", err)
+		}
+	}
+
+	svc, err := service.New(&program{}, svcConfig)
+	if err != nil {
+		writeLog("创建服务对象失败:", err)
+		fmt.Println("创建服务对象失败:", err)
+		os.Exit(1)
+	}
+
+	switch *svcFlag {
+	case "install":
+		if err := installService(); err != nil {
+			fmt.Println("安装服务失败:", err)
+			os.Exit(1)
+		}
+		fmt.Println("服务安装成功")
+		return
+	case "uninstall":
+		if err := uninstallService(); err != nil {
+			fmt.Println("卸载服务失败:", err)
+			os.Exit(1)
+		}
+		fmt.Println("服务卸载成功")
+		return
+	case "start":
+		if err := svc.Start(); err != nil {
+			fmt.Println("启动服务失败:", err)
+			os.Exit(1)
+		}
+		return
+	case "stop":
+		if err := svc.Stop(); err != nil {
+			fmt.Println("停止服务失败:", err)
+			os.Exit(1)
+		}
+		return
+	case "run":
+		// 由 SCM 拉起时不展示托盘 UI，只运行 HTTP 服务。
+		if err := svc.Run(); err != nil {
+			writeLog("服务运行错误:", err)
+		}
+		return
+	}
+
+	if !service.Interactive() {
+		// 没有显式传 -service run，但确实是被 SCM 拉起的，同样跳过托盘 UI。
+		if err := svc.Run(); err != nil {
+			writeLog("服务运行错误:", err)
+		}
+		return
+	}
+
+	// 交互式启动，保留原有托盘 UI。
 	systray.Run(onReady, onExit)
 }
 
@@ -91,7 +312,11 @@ func onReady() {
 	mStart := systray.AddMenuItem("启动服务", "启动 HTTP 服务")
 	mStop := systray.AddMenuItem("停止服务", "停止 HTTP 服务")
 	mLog := systray.AddMenuItem("打开日志", "查看日志文件")
+	mHistory := systray.AddMenuItem("查看历史", "在浏览器中打开短信历史")
+	mPair := systray.AddMenuItem("配对新手机", "生成二维码，供安卓端发送 App 扫码配对")
+	mReload := systray.AddMenuItem("重载配置", "重新读取 sms-service.yaml")
 	mAuto := systray.AddMenuItemCheckbox("开机自启", "开机时自动运行", isAutoRun())
+	mService := systray.AddMenuItemCheckbox("安装为系统服务", "安装/卸载系统服务 (SCM 管理，随系统重启恢复)", isServiceInstalled())
 	mQuit := systray.AddMenuItem("退出", "退出程序")
 
 	mStop.Disable()
@@ -135,8 +360,34 @@ func onReady() {
 					mAuto.Check()
 					writeLog("已启用开机自启")
 				}
+			case <-mService.ClickedCh:
+				if mService.Checked() {
+					if err := uninstallService(); err != nil {
+						writeLog("卸载系统服务失败:", err)
+					} else {
+						mService.Uncheck()
+						writeLog("已卸载系统服务")
+					}
+				} else {
+					if err := installService(); err != nil {
+						writeLog("安装系统服务失败:", err)
+					} else {
+						mService.Check()
+						writeLog("已安装系统服务")
+					}
+				}
+			case <-mHistory.ClickedCh:
+				if err := platform.Open(historyViewURL()); err != nil {
+					writeLog("打开历史查看页失败:", err)
+				}
+			case <-mPair.ClickedCh:
+				pairNewDevice()
+			case <-mReload.ClickedCh:
+				applyConfig(reloadConfig())
 			case <-mLog.ClickedCh:
-				exec.Command("notepad.exe", "sms-service.log").Start()
+				if err := platform.Open("sms-service.log"); err != nil {
+					writeLog("打开日志文件失败:", err)
+				}
 			case <-mQuit.ClickedCh:
 				onExit()
 				systray.Quit()
@@ -148,45 +399,69 @@ func onReady() {
 
 // ---------- Gin 服务 ----------
 func startServer() {
+	cfg := currentConfig()
+
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	// 不信任任何反向代理，ClientIP() 只看 RemoteAddr，
+	// 避免 X-Forwarded-For 被用来伪造成回环地址。
+	r.SetTrustedProxies(nil)
 
-	r.POST("/copy", func(c *gin.Context) {
+	r.POST("/copy", authMiddleware(), func(c *gin.Context) {
 		content := c.PostForm("content")
 		writeLog("收到 /copy 消息:", content)
 		showToast("手机短信", content)
 
-		reCode := regexp.MustCompile(`验证码[\s\S]*?(\d+)`)
-		matches := reCode.FindStringSubmatch(content)
-		code := ""
-		if len(matches) > 1 {
-			code = matches[1]
-			writeLog("提取验证码:", code)
+		cfg := currentConfig()
+		result := currentExtractor().Extract(content)
+		code := result.Code
+		if code != "" {
+			writeLog("提取验证码:", code, "规则:", result.Rule)
 		} else {
 			showToast("手机短信", "未找到验证码")
 			writeLog("未找到验证码")
 		}
 
-		if err := clipboard.Init(); err == nil {
-			clipboard.Write(clipboard.FmtText, []byte(code))
-			pasteClipboard()
+		if cfg.AutoPaste {
+			copyCodeToClipboard(code)
 		}
+
+		recordHistory("/copy", content, code, c.ClientIP())
+		dispatchForward(forwarder.Message{Time: time.Now(), Source: "/copy", Raw: content, Code: code})
 		c.String(200, "success")
 	})
 
-	r.POST("/msg", func(c *gin.Context) {
+	r.POST("/msg", authMiddleware(), func(c *gin.Context) {
 		content := c.PostForm("content")
 		if content != "" {
 			writeLog("收到 /msg 消息:", content)
 			showToast("手机消息", content)
+			recordHistory("/msg", content, "", c.ClientIP())
+			dispatchForward(forwarder.Message{Time: time.Now(), Source: "/msg", Raw: content})
 		}
 		c.String(200, "success")
 	})
 
+	r.GET("/config", guardReadOnly(), func(c *gin.Context) {
+		c.JSON(200, currentConfig())
+	})
+
+	r.POST("/extract", guardReadOnly(), func(c *gin.Context) {
+		content := c.PostForm("content")
+		c.JSON(200, currentExtractor().Extract(content))
+	})
+
+	r.GET("/ws", guardReadOnly(), gin.WrapH(wsHub))
+
+	registerHistoryRoutes(r)
+	registerAuthRoutes(r)
+
 	serverThread = &httpServerWrapper{
-		addr:   ":9002",
-		server: r,
-		stopCh: make(chan struct{}),
+		addr: cfg.Listen,
+		srv: &http.Server{
+			Addr:    cfg.Listen,
+			Handler: r,
+		},
 	}
 
 	serverThread.Start()
@@ -201,13 +476,9 @@ func stopServer() {
 // ---------- Toast ----------
 func showToast(title, msg string) {
 	iconPath, _ := extractIcon()
-	notification := toast.Notification{
-		AppID:   "短信服务",
-		Title:   title,
-		Message: msg,
-		Icon:    iconPath,
+	if err := notifier.Notify(currentConfig().ToastAppID, title, msg, iconPath); err != nil {
+		writeLog("发送系统通知失败:", err)
 	}
-	notification.Push()
 }
 
 func extractIcon() (string, error) {
@@ -220,24 +491,32 @@ func extractIcon() (string, error) {
 	return iconPath, nil
 }
 
+// copyCodeToClipboard 把验证码写入剪贴板并模拟粘贴，/copy 在开启 auto_paste 时
+// 和历史查看页的"复制验证码"按钮共用这条路径。
+func copyCodeToClipboard(code string) {
+	if err := clipboard.Init(); err != nil {
+		writeLog("剪贴板初始化失败:", err)
+		return
+	}
+	clipboard.Write(clipboard.FmtText, []byte(code))
+	pasteClipboard()
+}
+
 // ---------- 模拟粘贴 ----------
 func pasteClipboard() {
-	const KEYEVENTF_KEYUP = 0x0002
-	kbd := windows.NewLazySystemDLL("user32.dll").NewProc("keybd_event")
-	ctrl := byte(0x11)
-	v := byte(0x56)
-	kbd.Call(uintptr(ctrl), 0, 0, 0)
-	time.Sleep(100 * time.Millisecond)
-	kbd.Call(uintptr(v), 0, 0, 0)
-	time.Sleep(100 * time.Millisecond)
-	kbd.Call(uintptr(v), 0, KEYEVENTF_KEYUP, 0)
-	kbd.Call(uintptr(ctrl), 0, KEYEVENTF_KEYUP, 0)
-	writeLog("已执行 Ctrl+V 粘贴操作")
+	if err := platform.Paste(); err != nil {
+		writeLog("模拟粘贴失败:", err)
+		return
+	}
+	writeLog("已执行粘贴操作")
 }
 
 // ---------- 退出 ----------
 func onExit() {
 	stopServer()
+	if historyDB != nil {
+		historyDB.Close()
+	}
 	writeLog("程序退出")
 	logFile.Sync()
 	logFile.Close()
@@ -245,38 +524,17 @@ func onExit() {
 
 // ---------- 开机自启 ----------
 func enableAutoRun(name, path string) {
-	k, _, err := registry.CreateKey(registry.CURRENT_USER,
-		`Software\Microsoft\Windows\CurrentVersion\Run`,
-		registry.ALL_ACCESS)
-	if err != nil {
-		writeLog("注册表写入失败:", err)
-		return
-	}
-	defer k.Close()
-	err = k.SetStringValue(name, "\""+path+"\"")
-	if err != nil {
+	if err := autoRun.Enable(name, path); err != nil {
 		writeLog("设置开机启动项失败:", err)
 	}
 }
 
 func disableAutoRun() {
-	k, err := registry.OpenKey(registry.CURRENT_USER,
-		`Software\Microsoft\Windows\CurrentVersion\Run`,
-		registry.ALL_ACCESS)
-	if err == nil {
-		defer k.Close()
-		k.DeleteValue("sms-service")
+	if err := autoRun.Disable("sms-service"); err != nil {
+		writeLog("取消开机启动项失败:", err)
 	}
 }
 
 func isAutoRun() bool {
-	k, err := registry.OpenKey(registry.CURRENT_USER,
-		`Software\Microsoft\Windows\CurrentVersion\Run`,
-		registry.READ)
-	if err != nil {
-		return false
-	}
-	defer k.Close()
-	_, _, err = k.GetStringValue("sms-service")
-	return err == nil
+	return autoRun.IsEnabled("sms-service")
 }
@@ -0,0 +1,71 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+type darwinAutoRun struct{}
+
+func newAutoRun() AutoRun {
+	return darwinAutoRun{}
+}
+
+func plistPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", name+".plist"), nil
+}
+
+const plistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`
+
+func (darwinAutoRun) Enable(name, execPath string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(plistTemplate, name, execPath)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	return exec.Command("launchctl", "load", path).Run()
+}
+
+func (darwinAutoRun) Disable(name string) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	exec.Command("launchctl", "unload", path).Run()
+	return os.Remove(path)
+}
+
+func (darwinAutoRun) IsEnabled(name string) bool {
+	path, err := plistPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
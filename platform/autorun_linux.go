@@ -0,0 +1,57 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type linuxAutoRun struct{}
+
+func newAutoRun() AutoRun {
+	return linuxAutoRun{}
+}
+
+func desktopEntryPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "autostart", name+".desktop"), nil
+}
+
+const desktopEntryTemplate = `[Desktop Entry]
+Type=Application
+Name=%s
+Exec=%s
+X-GNOME-Autostart-enabled=true
+`
+
+func (linuxAutoRun) Enable(name, execPath string) error {
+	path, err := desktopEntryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf(desktopEntryTemplate, name, execPath)
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+func (linuxAutoRun) Disable(name string) error {
+	path, err := desktopEntryPath(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (linuxAutoRun) IsEnabled(name string) bool {
+	path, err := desktopEntryPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
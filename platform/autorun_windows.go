@@ -0,0 +1,39 @@
+package platform
+
+import "golang.org/x/sys/windows/registry"
+
+const runKeyPath = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+type windowsAutoRun struct{}
+
+func newAutoRun() AutoRun {
+	return windowsAutoRun{}
+}
+
+func (windowsAutoRun) Enable(name, execPath string) error {
+	k, _, err := registry.CreateKey(registry.CURRENT_USER, runKeyPath, registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetStringValue(name, "\""+execPath+"\"")
+}
+
+func (windowsAutoRun) Disable(name string) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.DeleteValue(name)
+}
+
+func (windowsAutoRun) IsEnabled(name string) bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.READ)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	_, _, err = k.GetStringValue(name)
+	return err == nil
+}
@@ -0,0 +1,17 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+type darwinNotifier struct{}
+
+func newNotifier() Notifier {
+	return darwinNotifier{}
+}
+
+func (darwinNotifier) Notify(appID, title, message, iconPath string) error {
+	script := fmt.Sprintf("display notification %q with title %q", message, title)
+	return exec.Command("osascript", "-e", script).Run()
+}
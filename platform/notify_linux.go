@@ -0,0 +1,17 @@
+package platform
+
+import "os/exec"
+
+type linuxNotifier struct{}
+
+func newNotifier() Notifier {
+	return linuxNotifier{}
+}
+
+func (linuxNotifier) Notify(appID, title, message, iconPath string) error {
+	args := []string{title, message}
+	if iconPath != "" {
+		args = append([]string{"-i", iconPath}, args...)
+	}
+	return exec.Command("notify-send", args...).Run()
+}
@@ -0,0 +1,19 @@
+package platform
+
+import "github.com/go-toast/toast"
+
+type windowsNotifier struct{}
+
+func newNotifier() Notifier {
+	return windowsNotifier{}
+}
+
+func (windowsNotifier) Notify(appID, title, message, iconPath string) error {
+	n := toast.Notification{
+		AppID:   appID,
+		Title:   title,
+		Message: message,
+		Icon:    iconPath,
+	}
+	return n.Push()
+}
@@ -0,0 +1,7 @@
+package platform
+
+import "os/exec"
+
+func open(target string) error {
+	return exec.Command("xdg-open", target).Start()
+}
@@ -0,0 +1,8 @@
+package platform
+
+import "os/exec"
+
+func paste() error {
+	script := `tell application "System Events" to keystroke "v" using command down`
+	return exec.Command("osascript", "-e", script).Run()
+}
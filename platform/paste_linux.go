@@ -0,0 +1,18 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// paste 优先用 ydotool（Wayland 下也能工作），退回 xdotool，
+// 两者都不存在时返回错误而不是静默失败。
+func paste() error {
+	if path, err := exec.LookPath("ydotool"); err == nil {
+		return exec.Command(path, "key", "ctrl+v").Run()
+	}
+	if path, err := exec.LookPath("xdotool"); err == nil {
+		return exec.Command(path, "key", "ctrl+v").Run()
+	}
+	return fmt.Errorf("未找到 xdotool 或 ydotool，无法模拟粘贴")
+}
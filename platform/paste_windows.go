@@ -0,0 +1,21 @@
+package platform
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+func paste() error {
+	const KEYEVENTF_KEYUP = 0x0002
+	kbd := windows.NewLazySystemDLL("user32.dll").NewProc("keybd_event")
+	ctrl := byte(0x11)
+	v := byte(0x56)
+	kbd.Call(uintptr(ctrl), 0, 0, 0)
+	time.Sleep(100 * time.Millisecond)
+	kbd.Call(uintptr(v), 0, 0, 0)
+	time.Sleep(100 * time.Millisecond)
+	kbd.Call(uintptr(v), 0, KEYEVENTF_KEYUP, 0)
+	kbd.Call(uintptr(ctrl), 0, KEYEVENTF_KEYUP, 0)
+	return nil
+}
@@ -0,0 +1,38 @@
+// Package platform 收纳所有跟操作系统强相关的实现：模拟粘贴、系统通知、
+// 开机自启。每个能力一个接口，具体实现按 Go 的平台文件名约定
+// （_windows.go / _darwin.go / _linux.go）分开，上层只依赖这里导出的类型和函数。
+package platform
+
+// Paste 模拟一次粘贴操作（Windows 上是 Ctrl+V，macOS 上是 Cmd+V）。
+func Paste() error {
+	return paste()
+}
+
+// Notifier 发一条系统通知。
+type Notifier interface {
+	Notify(appID, title, message, iconPath string) error
+}
+
+// NewNotifier 返回当前操作系统对应的 Notifier 实现。
+func NewNotifier() Notifier {
+	return newNotifier()
+}
+
+// AutoRun 管理"开机自启"的注册状态。
+type AutoRun interface {
+	Enable(name, execPath string) error
+	Disable(name string) error
+	IsEnabled(name string) bool
+}
+
+// NewAutoRun 返回当前操作系统对应的 AutoRun 实现。
+func NewAutoRun() AutoRun {
+	return newAutoRun()
+}
+
+// Open 用当前系统的默认方式打开一个文件路径或 URL（Windows 用 "start"，
+// macOS 用 "open"，Linux 用 "xdg-open"），供托盘菜单里"查看历史""配对新手机"
+// 这类需要跳出到外部程序的操作使用。
+func Open(target string) error {
+	return open(target)
+}